@@ -0,0 +1,97 @@
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+)
+
+// boundedWriter keeps only the first half and a rolling last half of
+// everything written to it, splicing the two together with a marker line
+// reporting how many bytes were elided. This bounds memory use for
+// long-running, chatty commands (compilers, R/NONMEM runs) while keeping
+// the diagnostics most useful in a post-mortem: how the command started
+// and how it ended.
+type boundedWriter struct {
+	half int
+
+	mu     sync.Mutex
+	head   bytes.Buffer
+	tail   []byte
+	elided int
+}
+
+// newBoundedWriter returns a boundedWriter that keeps roughly limit bytes
+// total: limit/2 from the start, limit/2 (rolling) from the end. A
+// non-positive limit disables bounding entirely. limit/2 is floored at 1
+// for any positive limit, so a tiny limit (e.g. 1) still bounds instead
+// of rounding down to 0 and buffering everything.
+func newBoundedWriter(limit int) *boundedWriter {
+	if limit <= 0 {
+		return &boundedWriter{}
+	}
+
+	half := limit / 2
+	if half < 1 {
+		half = 1
+	}
+
+	return &boundedWriter{half: half}
+}
+
+func (w *boundedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	n := len(p)
+
+	if w.half <= 0 {
+		w.head.Write(p)
+
+		return n, nil
+	}
+
+	if room := w.half - w.head.Len(); room > 0 {
+		if room > len(p) {
+			room = len(p)
+		}
+
+		w.head.Write(p[:room])
+		p = p[room:]
+	}
+
+	if len(p) == 0 {
+		return n, nil
+	}
+
+	w.tail = append(w.tail, p...)
+
+	if excess := len(w.tail) - w.half; excess > 0 {
+		// Advance to the next line boundary so we elide whole lines
+		// rather than cutting one in half.
+		cut := excess
+		if idx := bytes.IndexByte(w.tail[excess:], '\n'); idx >= 0 {
+			cut = excess + idx + 1
+		}
+
+		w.elided += cut
+		w.tail = w.tail[cut:]
+	}
+
+	return n, nil
+}
+
+// Result returns the spliced text, whether anything was elided, and how
+// many bytes were dropped.
+func (w *boundedWriter) Result() (text string, truncated bool, elided int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.elided == 0 {
+		return w.head.String() + string(w.tail), false, 0
+	}
+
+	marker := fmt.Sprintf("[... %d bytes elided ...]\n", w.elided)
+
+	return w.head.String() + marker + string(w.tail), true, w.elided
+}
@@ -0,0 +1,59 @@
+//go:build !windows
+// +build !windows
+
+package command_test
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/metrumresearchgroup/wrapt"
+
+	"github.com/metrumresearchgroup/command"
+)
+
+func TestCaptureOptions_MaxStderrBytes(tt *testing.T) {
+	t := WrapT(tt)
+
+	// Write 100 short lines to stderr; with a small limit, only the
+	// first and last few lines should survive, spliced around an elision
+	// marker.
+	script := `for i in $(seq 1 100); do echo "line $i" 1>&2; done`
+
+	got, err := command.CaptureOptions(nil, "/bin/bash", []string{"-c", script}, command.WithMaxStderrBytes(200))
+
+	t.A.NoError(err)
+	t.A.True(got.StderrTruncated)
+	t.A.True(got.StderrElidedBytes > 0)
+	t.A.True(strings.HasPrefix(got.Stderr, "line 1\n"))
+	t.A.True(strings.HasSuffix(got.Stderr, "line 100\n"))
+	t.A.Contains(got.Stderr, "bytes elided")
+}
+
+func TestCaptureOptions_MaxStderrBytes_NoTruncation(tt *testing.T) {
+	t := WrapT(tt)
+
+	got, err := command.CaptureOptions(nil, "/bin/bash", []string{"-c", `echo "short" 1>&2`}, command.WithMaxStderrBytes(1024))
+
+	t.A.NoError(err)
+	t.A.False(got.StderrTruncated)
+	t.A.Equal(0, got.StderrElidedBytes)
+	t.A.Equal("short\n", got.Stderr)
+}
+
+// TestCaptureOptions_MaxStderrBytes_BoundsOutput guards against
+// WithMaxStderrBytes capping Result.Stderr while leaving the combined
+// Result.Output unbounded, which would defeat the option's whole purpose
+// of keeping a chatty child's stderr from OOMing the parent.
+func TestCaptureOptions_MaxStderrBytes_BoundsOutput(tt *testing.T) {
+	t := WrapT(tt)
+
+	script := `for i in $(seq 1 20000); do echo "line $i" 1>&2; done`
+
+	got, err := command.CaptureOptions(nil, "/bin/bash", []string{"-c", script}, command.WithMaxStderrBytes(200))
+
+	t.A.NoError(err)
+	t.A.True(got.StderrTruncated)
+	t.A.True(len(got.Output) < 1024, "Output should be bounded, got %d bytes", len(got.Output))
+	t.A.Equal(got.Stderr, got.Output)
+}
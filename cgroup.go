@@ -0,0 +1,57 @@
+package command
+
+import "time"
+
+// CgroupSpec configures the Linux cgroup a Cmd's child process should run
+// in, bounding its CPU, memory, and process count. See WithCgroup.
+type CgroupSpec struct {
+	// Parent names the parent cgroup (a path relative to the relevant
+	// cgroup mount) under which a per-invocation child cgroup is created,
+	// e.g. "myapp.slice". Required.
+	Parent string
+
+	// MemoryLimit caps the child's memory usage in bytes. Zero means unset.
+	MemoryLimit int64
+
+	// CPUShares sets the child's relative CPU weight (cgroup v1 cpu.shares,
+	// or passed through as cgroup v2 cpu.weight). Zero means unset.
+	CPUShares int64
+
+	// PidsMax caps the number of processes/threads the child may create.
+	// Zero means unset.
+	PidsMax int64
+}
+
+// Resource reports resource accounting collected from the cgroup a
+// command ran in. It is only populated on Cmd after Wait when the Cmd was
+// configured WithCgroup, and only on Linux; it is always the zero value
+// elsewhere.
+type Resource struct {
+	// MemoryPeakBytes is the peak memory usage recorded for the cgroup.
+	MemoryPeakBytes int64
+
+	// MemoryMaxUsageBytes is memory.max_usage_in_bytes (v1) or
+	// memory.peak (v2); the two controllers don't expose identical
+	// counters, so this mirrors whichever one is available.
+	MemoryMaxUsageBytes int64
+
+	// CPUTimeTotal is the total CPU time charged to the cgroup.
+	CPUTimeTotal time.Duration
+}
+
+// WithCgroup makes Start place the child into a new cgroup under
+// spec.Parent immediately after it starts, bounding its CPU/memory/pids
+// per spec. Wait then populates c.Resource from the cgroup's accounting
+// files and removes the cgroup. Both cgroup v1 (per-controller
+// hierarchies under /sys/fs/cgroup/<controller>) and v2 (the unified
+// hierarchy under /sys/fs/cgroup) are supported; which is in use is
+// detected by checking for /sys/fs/cgroup/cgroup.controllers.
+//
+// This is Linux-only; on other platforms it compiles to a no-op. It has no
+// effect on the package-level Capture/CaptureContext functions, which
+// don't run through a Cmd.
+func (c *Cmd) WithCgroup(spec CgroupSpec) *Cmd {
+	c.cgroupSpec = &spec
+
+	return c
+}
@@ -0,0 +1,191 @@
+//go:build linux
+// +build linux
+
+package command
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cgroupHandle tracks the cgroup created for a single Cmd invocation so
+// resource() and remove() know where to look.
+type cgroupHandle struct {
+	v2 bool
+
+	// path is, for v2, the absolute path to the cgroup directory. For v1
+	// it is the path relative to /sys/fs/cgroup/<controller>, since each
+	// controller has its own hierarchy rooted there.
+	path string
+}
+
+var cgroupV1Controllers = []string{"memory", "cpu", "pids"}
+
+func cgroupIsV2() bool {
+	_, err := os.Stat("/sys/fs/cgroup/cgroup.controllers")
+
+	return err == nil
+}
+
+// newCgroup creates a per-invocation cgroup under spec.Parent, applies
+// spec's limits, and writes pid into its cgroup.procs.
+func newCgroup(spec CgroupSpec, pid int) (*cgroupHandle, error) {
+	name := fmt.Sprintf("command-%d", pid)
+
+	if cgroupIsV2() {
+		return newCgroupV2(spec, name, pid)
+	}
+
+	return newCgroupV1(spec, name, pid)
+}
+
+func newCgroupV2(spec CgroupSpec, name string, pid int) (*cgroupHandle, error) {
+	dir := filepath.Join("/sys/fs/cgroup", spec.Parent, name)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	if spec.MemoryLimit > 0 {
+		if err := writeCgroupFile(filepath.Join(dir, "memory.max"), spec.MemoryLimit); err != nil {
+			return nil, err
+		}
+	}
+
+	if spec.CPUShares > 0 {
+		if err := writeCgroupFile(filepath.Join(dir, "cpu.weight"), spec.CPUShares); err != nil {
+			return nil, err
+		}
+	}
+
+	if spec.PidsMax > 0 {
+		if err := writeCgroupFile(filepath.Join(dir, "pids.max"), spec.PidsMax); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writeCgroupFile(filepath.Join(dir, "cgroup.procs"), int64(pid)); err != nil {
+		return nil, err
+	}
+
+	return &cgroupHandle{v2: true, path: dir}, nil
+}
+
+func newCgroupV1(spec CgroupSpec, name string, pid int) (*cgroupHandle, error) {
+	rel := filepath.Join(spec.Parent, name)
+
+	for _, ctrl := range cgroupV1Controllers {
+		if err := os.MkdirAll(filepath.Join("/sys/fs/cgroup", ctrl, rel), 0o755); err != nil {
+			return nil, err
+		}
+	}
+
+	if spec.MemoryLimit > 0 {
+		if err := writeCgroupFile(filepath.Join("/sys/fs/cgroup/memory", rel, "memory.limit_in_bytes"), spec.MemoryLimit); err != nil {
+			return nil, err
+		}
+	}
+
+	if spec.CPUShares > 0 {
+		if err := writeCgroupFile(filepath.Join("/sys/fs/cgroup/cpu", rel, "cpu.shares"), spec.CPUShares); err != nil {
+			return nil, err
+		}
+	}
+
+	if spec.PidsMax > 0 {
+		if err := writeCgroupFile(filepath.Join("/sys/fs/cgroup/pids", rel, "pids.max"), spec.PidsMax); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, ctrl := range cgroupV1Controllers {
+		if err := writeCgroupFile(filepath.Join("/sys/fs/cgroup", ctrl, rel, "cgroup.procs"), int64(pid)); err != nil {
+			return nil, err
+		}
+	}
+
+	return &cgroupHandle{v2: false, path: rel}, nil
+}
+
+func writeCgroupFile(path string, value int64) error {
+	return os.WriteFile(path, []byte(strconv.FormatInt(value, 10)), 0o644)
+}
+
+func (h *cgroupHandle) resource() Resource {
+	if h.v2 {
+		peak := readCgroupInt(filepath.Join(h.path, "memory.peak"))
+
+		return Resource{
+			MemoryPeakBytes:     peak,
+			MemoryMaxUsageBytes: peak,
+			CPUTimeTotal:        readCPUStatV2(filepath.Join(h.path, "cpu.stat")),
+		}
+	}
+
+	maxUsage := readCgroupInt(filepath.Join("/sys/fs/cgroup/memory", h.path, "memory.max_usage_in_bytes"))
+
+	return Resource{
+		MemoryPeakBytes:     maxUsage,
+		MemoryMaxUsageBytes: maxUsage,
+		CPUTimeTotal:        time.Duration(readCgroupInt(filepath.Join("/sys/fs/cgroup/cpu", h.path, "cpuacct.usage"))) * time.Nanosecond,
+	}
+}
+
+// remove deletes the cgroup directories created by newCgroup. A cgroup
+// can only be removed once it has no member processes, which Wait
+// guarantees by calling this after the child has exited.
+func (h *cgroupHandle) remove() error {
+	if h.v2 {
+		return os.Remove(h.path)
+	}
+
+	var firstErr error
+
+	for _, ctrl := range cgroupV1Controllers {
+		if err := os.Remove(filepath.Join("/sys/fs/cgroup", ctrl, h.path)); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+func readCgroupInt(path string) int64 {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+
+	n, _ := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+
+	return n
+}
+
+// readCPUStatV2 extracts usage_usec from a v2 cpu.stat file, e.g.:
+//
+//	usage_usec 1234
+//	user_usec 1000
+//	system_usec 234
+func readCPUStatV2(path string) time.Duration {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "usage_usec" {
+			usec, _ := strconv.ParseInt(fields[1], 10, 64)
+
+			return time.Duration(usec) * time.Microsecond
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,21 @@
+//go:build !linux
+// +build !linux
+
+package command
+
+// cgroupHandle is a no-op stub: cgroups are a Linux kernel facility with
+// no equivalent elsewhere, mirroring how Impersonate's Setpgid semantics
+// differ per platform.
+type cgroupHandle struct{}
+
+func newCgroup(_ CgroupSpec, _ int) (*cgroupHandle, error) {
+	return &cgroupHandle{}, nil
+}
+
+func (h *cgroupHandle) resource() Resource {
+	return Resource{}
+}
+
+func (h *cgroupHandle) remove() error {
+	return nil
+}
@@ -4,15 +4,32 @@ import (
 	"context"
 	"errors"
 	"os/exec"
-	user "os/user"
-	"strconv"
-	"syscall"
 	"time"
 )
 
 type Cmd struct {
 	*exec.Cmd
 	cancelFunc func()
+
+	// killGroup, when true, makes Start place the child in its own
+	// process group/job and makes Kill/KillAfter/KillTimer terminate that
+	// whole tree rather than only the direct child. Set via WithKillGroup.
+	killGroup bool
+
+	// job holds the Windows Job Object WithKillGroup attaches to the
+	// child at Start, letting killGroup guarantee the whole tree is torn
+	// down via TerminateJobObject. It's always nil on POSIX, where
+	// killGroup instead targets the process group created via Setpgid.
+	job *jobHandle
+
+	// cgroupSpec, when set via WithCgroup, makes Start place the child
+	// into a per-invocation cgroup, and Wait collect Resource from it.
+	cgroupSpec *CgroupSpec
+	cgroup     *cgroupHandle
+
+	// Resource holds accounting collected from the cgroup the child ran
+	// in, populated by Wait when WithCgroup was used.
+	Resource Resource
 }
 
 func New(name string, args ...string) *Cmd {
@@ -30,16 +47,83 @@ func NewWithContext(ctx context.Context, name string, args ...string) *Cmd {
 	}
 }
 
+// WithKillGroup makes Kill/KillAfter/KillTimer terminate the entire
+// process tree spawned by this Cmd instead of only the direct child. A
+// shell wrapper (e.g. `/bin/sh -c ...`) otherwise leaves its own children
+// behind when only the direct child is signaled. Unlike the setPgid
+// argument to Impersonate, this doesn't require impersonating anyone: it
+// makes Start place the child in its own process group on its own.
+func (c *Cmd) WithKillGroup() *Cmd {
+	c.killGroup = true
+
+	return c
+}
+
+// Start starts the command same as the embedded *exec.Cmd, then, if
+// WithCgroup was used, creates the child's cgroup and adds it to
+// cgroup.procs.
+func (c *Cmd) Start() error {
+	if c.killGroup {
+		ensureProcessGroup(c)
+	}
+
+	if err := c.Cmd.Start(); err != nil {
+		return err
+	}
+
+	if c.killGroup {
+		if err := attachKillGroup(c); err != nil {
+			return err
+		}
+	}
+
+	if c.cgroupSpec != nil {
+		handle, err := newCgroup(*c.cgroupSpec, c.Process.Pid)
+		if err != nil {
+			return err
+		}
+
+		c.cgroup = handle
+	}
+
+	return nil
+}
+
+// Wait waits for the command same as the embedded *exec.Cmd, then, if
+// WithCgroup was used, populates c.Resource from the cgroup's accounting
+// files and removes the cgroup.
+func (c *Cmd) Wait() error {
+	err := c.Cmd.Wait()
+
+	if c.cgroup != nil {
+		c.Resource = c.cgroup.resource()
+		_ = c.cgroup.remove()
+	}
+
+	return err
+}
+
 // Kill ends a process. Its operation depends on whether you created the Cmd
-// with a context or not.
+// with a context or not. If WithKillGroup was set, the entire process
+// group/tree is terminated instead of only the direct child.
 func (c *Cmd) Kill() error {
 	if c.cancelFunc != nil {
+		// context.CancelFunc only unblocks ctx.Done(); the exec package's
+		// default reaction to that is killing the direct child, which
+		// would make WithKillGroup a no-op for a NewWithContext Cmd. Kill
+		// the group ourselves first so it has the same effect here as it
+		// does for a Cmd started without a context.
+		if c.killGroup && c.Process != nil {
+			_ = killGroup(c)
+		}
+
 		c.cancelFunc()
 
 		return c.Wait()
 	}
+
 	if c.Process != nil {
-		if err := c.Process.Kill(); err != nil {
+		if err := c.killProcess(); err != nil {
 			return err
 		}
 
@@ -49,6 +133,14 @@ func (c *Cmd) Kill() error {
 	return errors.New("not running")
 }
 
+func (c *Cmd) killProcess() error {
+	if c.killGroup {
+		return killGroup(c)
+	}
+
+	return c.Process.Kill()
+}
+
 // KillTimer waits for the duration stated and then sends back the results
 // of calling Kill via the errCh channel.
 func (c *Cmd) KillTimer(d time.Duration, errCh chan<- error) {
@@ -64,63 +156,3 @@ func (c *Cmd) KillAfter(t time.Time, errCh chan<- error) {
 	d := time.Until(t)
 	c.KillTimer(d, errCh)
 }
-
-// Impersonate does a sets the SysProcAttrs to impersonate a permitted
-// user.
-func (c *Cmd) Impersonate(username string, setPgid bool) error {
-	usr, cred, err := userCredential(username)
-	if err != nil {
-		return err
-	}
-
-	c.SysProcAttr = &syscall.SysProcAttr{
-		Setpgid:    setPgid,
-		Credential: cred,
-	}
-
-	if len(usr.Username) != 0 {
-		c.Env = append(c.Env, "USER="+usr.Username)
-	}
-
-	if len(usr.HomeDir) != 0 {
-		c.Env = append(c.Env, "HOME="+usr.HomeDir)
-	}
-
-	return nil
-}
-
-func userCredential(username string) (*user.User, *syscall.Credential, error) {
-	if len(username) == 0 {
-		return nil, nil, errors.New("username empty")
-	}
-
-	var (
-		u *user.User
-		c syscall.Credential
-	)
-	{
-		var err error
-
-		u, err = user.Lookup(username)
-		if err != nil {
-			return nil, nil, err
-		}
-
-		uid, err := strconv.Atoi(u.Uid)
-		if err != nil {
-			return nil, nil, err
-		}
-
-		gid, err := strconv.Atoi(u.Gid)
-		if err != nil {
-			return nil, nil, err
-		}
-
-		c = syscall.Credential{
-			Uid: uint32(uid),
-			Gid: uint32(gid),
-		}
-	}
-
-	return u, &c, nil
-}
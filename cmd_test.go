@@ -7,6 +7,7 @@ import (
 	"os/exec"
 	"os/user"
 	"strconv"
+	"strings"
 	"syscall"
 	"testing"
 	"time"
@@ -79,6 +80,129 @@ func TestCmd_KillAfter(tt *testing.T) {
 	}
 }
 
+// TestCmd_WithKillGroup_killsGrandchildren guards against WithKillGroup
+// flipping killGroup without ever putting the child in its own process
+// group: if Start didn't call ensureProcessGroup, Kill would signal a
+// process group that doesn't exist (ESRCH), silently leaving the
+// grandchild the shell backgrounds running.
+func TestCmd_WithKillGroup_killsGrandchildren(tt *testing.T) {
+	t := wrapt.WrapT(tt)
+
+	pidFile, err := os.CreateTemp("", "killgroup-pid")
+	t.R.NoError(err)
+	t.R.NoError(pidFile.Close())
+
+	defer os.Remove(pidFile.Name())
+
+	c := New("bash", "-c", `sleep 100 & echo $! > `+pidFile.Name()+`; wait`).WithKillGroup()
+	t.R.NoError(c.Start())
+
+	var grandchildPid int
+
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		data, err := os.ReadFile(pidFile.Name())
+		if err != nil || len(strings.TrimSpace(string(data))) == 0 {
+			continue
+		}
+
+		grandchildPid, err = strconv.Atoi(strings.TrimSpace(string(data)))
+		t.R.NoError(err)
+
+		break
+	}
+
+	t.R.True(grandchildPid > 0, "grandchild never reported its pid")
+
+	// Kill's Wait picks up the SIGKILL as a non-nil error; that's
+	// expected, since the whole group, including the shell, was killed.
+	t.R.Error(c.Kill())
+
+	// The grandchild gets SIGKILL the same instant as the shell, but it
+	// may briefly linger as a zombie (and, once orphaned, is never
+	// reaped by this sandbox's init), so check process state via /proc
+	// rather than relying on it fully disappearing.
+	var dead bool
+
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if grandchildDead(grandchildPid) {
+			dead = true
+
+			break
+		}
+	}
+
+	t.R.True(dead, "grandchild %d was still running after WithKillGroup().Kill()", grandchildPid)
+}
+
+// TestCmd_Impersonate_setPgid_killsGrandchildren guards against
+// Impersonate(_, true) setting Setpgid without also setting killGroup:
+// Kill/killProcess gate group termination on killGroup alone, so a Cmd
+// that impersonated with setPgid but never called WithKillGroup would
+// otherwise only kill the direct child and leak the grandchild, the same
+// failure WithKillGroup itself was fixed against.
+func TestCmd_Impersonate_setPgid_killsGrandchildren(tt *testing.T) {
+	t := wrapt.WrapT(tt)
+
+	u, err := user.Current()
+	t.R.NoError(err)
+
+	pidFile, err := os.CreateTemp("", "impersonate-pgid-pid")
+	t.R.NoError(err)
+	t.R.NoError(pidFile.Close())
+
+	defer os.Remove(pidFile.Name())
+
+	c := New("bash", "-c", `sleep 100 & echo $! > `+pidFile.Name()+`; wait`)
+	t.R.NoError(c.Impersonate(u.Username, true))
+	t.R.NoError(c.Start())
+
+	var grandchildPid int
+
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		data, err := os.ReadFile(pidFile.Name())
+		if err != nil || len(strings.TrimSpace(string(data))) == 0 {
+			continue
+		}
+
+		grandchildPid, err = strconv.Atoi(strings.TrimSpace(string(data)))
+		t.R.NoError(err)
+
+		break
+	}
+
+	t.R.True(grandchildPid > 0, "grandchild never reported its pid")
+
+	t.R.Error(c.Kill())
+
+	var dead bool
+
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if grandchildDead(grandchildPid) {
+			dead = true
+
+			break
+		}
+	}
+
+	t.R.True(dead, "grandchild %d was still running after Impersonate(_, true).Kill()", grandchildPid)
+}
+
+// grandchildDead reports whether pid is gone or a zombie, using /proc
+// instead of signal 0: a killed grandchild reparented to this sandbox's
+// init can sit as a zombie forever if nothing reaps it, so checking that
+// it was actually signaled, not that it was fully reaped, is what
+// WithKillGroup promises.
+func grandchildDead(pid int) bool {
+	data, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return true
+	}
+
+	fields := strings.Fields(string(data))
+
+	return len(fields) > 2 && fields[2] == "Z"
+}
+
 func TestCmd_Impersonate(tt *testing.T) {
 	type fields struct {
 		Cmd        *exec.Cmd
@@ -0,0 +1,33 @@
+// Package fdtest detects file-descriptor leaks around command execution,
+// in the spirit of the haveUnexpectedFDs check in Go's own os/exec test
+// suite. It exists because Pipes, WireIO, and pipes.Attach all hand back
+// raw pipe ends that the caller must close; forgetting one, or a process
+// that fails between the three *Pipe() calls, leaks file descriptors
+// silently until this guard is used in a test.
+package fdtest
+
+import "testing"
+
+// Guard snapshots the number of open file descriptors held by the test
+// process, then registers a t.Cleanup that fails t if that number grew by
+// the time the test finishes. Call it at the top of any test that uses
+// Pipes, WireIO, or pipes.Attach.
+func Guard(t *testing.T) {
+	t.Helper()
+
+	before, err := openFDCount()
+	if err != nil {
+		t.Fatalf("fdtest: snapshot file descriptors: %v", err)
+	}
+
+	t.Cleanup(func() {
+		after, err := openFDCount()
+		if err != nil {
+			t.Fatalf("fdtest: snapshot file descriptors: %v", err)
+		}
+
+		if after > before {
+			t.Errorf("fdtest: leaked %d file descriptor(s): started with %d, ended with %d", after-before, before, after)
+		}
+	})
+}
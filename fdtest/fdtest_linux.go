@@ -0,0 +1,43 @@
+//go:build linux
+// +build linux
+
+package fdtest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// openFDCount walks /proc/self/fd, which on Linux lists exactly the open
+// file descriptors of the calling process, skipping the runtime's own
+// netpoller descriptors (epoll/eventfd/timerfd): those come and go on
+// their own schedule, independent of anything the test under Guard did,
+// and would otherwise read as a phantom leak.
+func openFDCount() (int, error) {
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+
+	for _, e := range entries {
+		target, err := os.Readlink(filepath.Join("/proc/self/fd", e.Name()))
+		if err == nil && isPollFD(target) {
+			continue
+		}
+
+		count++
+	}
+
+	return count, nil
+}
+
+// isPollFD reports whether target, the symlink target of a /proc/self/fd
+// entry, is one of the Go runtime's internal netpoller descriptors.
+func isPollFD(target string) bool {
+	return strings.Contains(target, "anon_inode:[eventpoll]") ||
+		strings.Contains(target, "anon_inode:[eventfd]") ||
+		strings.Contains(target, "anon_inode:[timerfd]")
+}
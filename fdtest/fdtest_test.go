@@ -0,0 +1,28 @@
+//go:build !windows
+// +build !windows
+
+package fdtest_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/metrumresearchgroup/command/fdtest"
+)
+
+func TestGuard_NoLeak(t *testing.T) {
+	fdtest.Guard(t)
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
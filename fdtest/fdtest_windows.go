@@ -0,0 +1,33 @@
+//go:build windows
+// +build windows
+
+package fdtest
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var (
+	kernel32                  = syscall.NewLazyDLL("kernel32.dll")
+	procGetProcessHandleCount = kernel32.NewProc("GetProcessHandleCount")
+)
+
+// openFDCount uses GetProcessHandleCount, Windows' equivalent of a file
+// descriptor count, since there is no per-handle enumeration available
+// without administrative privileges.
+func openFDCount() (int, error) {
+	var count uint32
+
+	h, err := syscall.GetCurrentProcess()
+	if err != nil {
+		return 0, err
+	}
+
+	ret, _, callErr := procGetProcessHandleCount.Call(uintptr(h), uintptr(unsafe.Pointer(&count)))
+	if ret == 0 {
+		return 0, callErr
+	}
+
+	return int(count), nil
+}
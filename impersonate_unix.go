@@ -0,0 +1,122 @@
+//go:build !windows
+// +build !windows
+
+package command
+
+import (
+	"errors"
+	"os"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// Impersonate sets the SysProcAttr to impersonate a permitted user. When
+// setPgid is true, the child is started in its own process group and
+// killGroup is set so Kill/KillAfter/KillTimer and Shutdown/Stop target
+// the whole tree it spawns, the same as if WithKillGroup had been called.
+func (c *Cmd) Impersonate(username string, setPgid bool) error {
+	usr, cred, err := userCredential(username)
+	if err != nil {
+		return err
+	}
+
+	c.SysProcAttr = &syscall.SysProcAttr{
+		Setpgid:    setPgid,
+		Credential: cred,
+	}
+
+	if setPgid {
+		c.killGroup = true
+	}
+
+	if len(usr.Username) != 0 {
+		c.Env = append(c.Env, "USER="+usr.Username)
+	}
+
+	if len(usr.HomeDir) != 0 {
+		c.Env = append(c.Env, "HOME="+usr.HomeDir)
+	}
+
+	return nil
+}
+
+func userCredential(username string) (*user.User, *syscall.Credential, error) {
+	if len(username) == 0 {
+		return nil, nil, errors.New("username empty")
+	}
+
+	var (
+		u *user.User
+		c syscall.Credential
+	)
+	{
+		var err error
+
+		u, err = user.Lookup(username)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		uid, err := strconv.Atoi(u.Uid)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		gid, err := strconv.Atoi(u.Gid)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		c = syscall.Credential{
+			Uid: uint32(uid),
+			Gid: uint32(gid),
+		}
+	}
+
+	return u, &c, nil
+}
+
+// ensureProcessGroup makes the child start in its own process group,
+// setting SysProcAttr.Setpgid without disturbing anything else Impersonate
+// may already have configured. Called from Start when WithKillGroup was
+// used, so killGroup has an actual group to target.
+func ensureProcessGroup(c *Cmd) {
+	if c.SysProcAttr == nil {
+		c.SysProcAttr = &syscall.SysProcAttr{}
+	}
+
+	c.SysProcAttr.Setpgid = true
+}
+
+// jobHandle is a no-op stub on POSIX: kill(2) against the negated pid of
+// a process group already reaches every member of the tree, so there's
+// no separate object to attach the way Windows needs a Job Object.
+type jobHandle struct{}
+
+// attachKillGroup is a no-op on POSIX; ensureProcessGroup already set up
+// everything killGroup needs at Start.
+func attachKillGroup(c *Cmd) error {
+	return nil
+}
+
+// killGroup signals SIGKILL to the entire process group led by c.Process,
+// which is only a distinct group when the Cmd was started with
+// Setpgid (see ensureProcessGroup and Impersonate). The pid of the group
+// leader doubles as its pgid, so negating it targets the whole group per
+// kill(2).
+func killGroup(c *Cmd) error {
+	return syscall.Kill(-c.Process.Pid, syscall.SIGKILL)
+}
+
+// terminateProcess sends SIGTERM to p, the POSIX signal for asking a
+// process to exit gracefully.
+func terminateProcess(p *os.Process) error {
+	return p.Signal(syscall.SIGTERM)
+}
+
+// terminateGroup sends SIGTERM to the entire process group led by
+// c.Process; see killGroup for why negating the pid targets the group.
+func terminateGroup(c *Cmd) error {
+	return syscall.Kill(-c.Process.Pid, syscall.SIGTERM)
+}
@@ -0,0 +1,180 @@
+//go:build windows
+// +build windows
+
+package command
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	logon32LogonInteractive = 2
+	logon32ProviderDefault  = 0
+
+	// processAllAccess is PROCESS_ALL_ACCESS, which syscall doesn't
+	// define itself; needed to open a handle attachKillGroup can assign
+	// to a Job Object.
+	processAllAccess = 0x1F0FFF
+)
+
+var (
+	advapi32              = syscall.NewLazyDLL("advapi32.dll")
+	procLogonUserW        = advapi32.NewProc("LogonUserW")
+	kernel32              = syscall.NewLazyDLL("kernel32.dll")
+	procGenerateCtrlBreak = kernel32.NewProc("GenerateConsoleCtrlEvent")
+	procCreateJobObjectW  = kernel32.NewProc("CreateJobObjectW")
+	procAssignProcessJob  = kernel32.NewProc("AssignProcessToJobObject")
+	procTerminateJobObj   = kernel32.NewProc("TerminateJobObject")
+)
+
+// Impersonate sets the SysProcAttr Token to run the child as username via
+// LogonUserW, using an empty domain and password; this only succeeds for
+// accounts Windows permits to log on without a password (e.g. via a
+// configured security policy). When setPgid is true, the child is placed
+// in its own process group (CREATE_NEW_PROCESS_GROUP) and killGroup is
+// set so Kill/KillAfter/KillTimer and Shutdown/Stop target the whole tree
+// it spawns, the same as if WithKillGroup had been called.
+func (c *Cmd) Impersonate(username string, setPgid bool) error {
+	if len(username) == 0 {
+		return errors.New("username empty")
+	}
+
+	token, err := logonUser(username)
+	if err != nil {
+		return err
+	}
+
+	attr := &syscall.SysProcAttr{Token: token}
+	if setPgid {
+		attr.CreationFlags |= syscall.CREATE_NEW_PROCESS_GROUP
+		c.killGroup = true
+	}
+
+	c.SysProcAttr = attr
+
+	return nil
+}
+
+func logonUser(username string) (syscall.Token, error) {
+	usernamePtr, err := syscall.UTF16PtrFromString(username)
+	if err != nil {
+		return 0, err
+	}
+
+	var token syscall.Token
+
+	ret, _, err := procLogonUserW.Call(
+		uintptr(unsafe.Pointer(usernamePtr)),
+		0, // domain: use the local machine
+		0, // password: none
+		uintptr(logon32LogonInteractive),
+		uintptr(logon32ProviderDefault),
+		uintptr(unsafe.Pointer(&token)),
+	)
+	if ret == 0 {
+		return 0, err
+	}
+
+	return token, nil
+}
+
+// ensureProcessGroup makes the child start in its own console process
+// group, setting CREATE_NEW_PROCESS_GROUP without disturbing anything
+// else Impersonate may already have configured. Called from Start when
+// WithKillGroup was used, so terminateGroup has a group to Ctrl+Break.
+func ensureProcessGroup(c *Cmd) {
+	if c.SysProcAttr == nil {
+		c.SysProcAttr = &syscall.SysProcAttr{}
+	}
+
+	c.SysProcAttr.CreationFlags |= syscall.CREATE_NEW_PROCESS_GROUP
+}
+
+// jobHandle wraps a Windows Job Object. Unlike a console process group,
+// which only lets you deliver Ctrl+Break/Ctrl+C, a Job Object can be torn
+// down unconditionally with TerminateJobObject, which is what lets
+// killGroup guarantee the whole tree is gone rather than only whichever
+// descendants chose to handle a break signal.
+type jobHandle struct {
+	handle syscall.Handle
+}
+
+// attachKillGroup creates a Job Object and assigns the freshly started
+// child to it, so killGroup can later terminate the whole tree through
+// it. There's an inherent race between Start returning and this call:
+// if the child spawns and exits a grandchild in between, that grandchild
+// was never a job member, same limitation every out-of-process job
+// attachment on Windows has without CREATE_SUSPENDED.
+func attachKillGroup(c *Cmd) error {
+	h, _, err := procCreateJobObjectW.Call(0, 0)
+	if h == 0 {
+		return err
+	}
+
+	job := &jobHandle{handle: syscall.Handle(h)}
+
+	proc, err := syscall.OpenProcess(processAllAccess, false, uint32(c.Process.Pid))
+	if err != nil {
+		return err
+	}
+	defer syscall.CloseHandle(proc)
+
+	ret, _, err := procAssignProcessJob.Call(uintptr(job.handle), uintptr(proc))
+	if ret == 0 {
+		return err
+	}
+
+	c.job = job
+
+	return nil
+}
+
+// terminate tears down every process in the job in one call, regardless
+// of whether any of them installed a Ctrl+Break handler.
+func (j *jobHandle) terminate() error {
+	ret, _, err := procTerminateJobObj.Call(uintptr(j.handle), 1)
+	if ret == 0 {
+		return err
+	}
+
+	return nil
+}
+
+// killGroup guarantees the entire process tree led by c.Process is gone,
+// using the Job Object attachKillGroup attached at Start. Falls back to
+// killing just the direct child if no Job Object is attached, e.g. Start
+// predates WithKillGroup being added, or CreateJobObjectW failed.
+func killGroup(c *Cmd) error {
+	if c.job != nil {
+		return c.job.terminate()
+	}
+
+	return c.Process.Kill()
+}
+
+// terminateProcess has no SIGTERM equivalent to fall back to for an
+// arbitrary Windows process, so it's a hard kill.
+func terminateProcess(p *os.Process) error {
+	return p.Kill()
+}
+
+// terminateGroup delivers a Ctrl+Break to the console process group led
+// by c.Process, which is only a distinct group when the Cmd was started
+// with CREATE_NEW_PROCESS_GROUP (see ensureProcessGroup and Impersonate).
+// The pid of the group leader doubles as the process group id passed to
+// GenerateConsoleCtrlEvent. This is the closest thing Windows has to a
+// graceful shutdown signal for console processes; killGroup is the hard
+// stop once grace runs out.
+func terminateGroup(c *Cmd) error {
+	const ctrlBreakEvent = 1
+
+	ret, _, err := procGenerateCtrlBreak.Call(uintptr(ctrlBreakEvent), uintptr(c.Process.Pid))
+	if ret == 0 {
+		return err
+	}
+
+	return nil
+}
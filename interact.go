@@ -1,12 +1,34 @@
 package command
 
-import "bufio"
+import (
+	"bufio"
+	"io"
+	"sync"
+)
 
 type Interact struct {
 	Plumber
 	Controller
 
 	outScanner, errScanner *bufio.Scanner
+
+	// MaxScanTokenSize sets the buffer size used by the scanners
+	// WithLineHandler starts, letting lines longer than bufio.Scanner's
+	// default 64KB survive. Zero uses the default. Set it before calling
+	// WithLineHandler.
+	MaxScanTokenSize int
+
+	// MaxStderrBytes, when positive, caps what Stderr reports to roughly
+	// that many bytes, the same way WithMaxStderrBytes bounds Capture's
+	// Result.Stderr: the first half is kept verbatim, the last half is
+	// kept as a rolling window, and the two are spliced together with a
+	// marker line reporting how many bytes were elided. Set it before the
+	// first call to StderrScanner, directly or via WithLineHandler.
+	MaxStderrBytes int
+
+	stderrBound *boundedWriter
+
+	lineWG *sync.WaitGroup
 }
 
 // Plumber defines functions on Pipes that handle modes of interaction.
@@ -33,17 +55,101 @@ func (i *Interact) StdoutScanner() *bufio.Scanner {
 	return i.outScanner
 }
 
-// StderrScanner returns a bufio.Scanner over stderr.
+// StderrScanner returns a bufio.Scanner over stderr. When MaxStderrBytes
+// is positive, the raw bytes are also teed into a boundedWriter that
+// Stderr reports from, so callers get a capped view of stderr without
+// giving up line-by-line scanning.
 func (i *Interact) StderrScanner() *bufio.Scanner {
 	if i.errScanner != nil {
 		return i.errScanner
 	}
 
-	i.errScanner = bufio.NewScanner(i.Plumber.Pipes().Stderr)
+	r := i.Plumber.Pipes().Stderr
+	if i.MaxStderrBytes > 0 {
+		i.stderrBound = newBoundedWriter(i.MaxStderrBytes)
+		r = io.TeeReader(r, i.stderrBound)
+	}
+
+	i.errScanner = bufio.NewScanner(r)
 
 	return i.errScanner
 }
 
+// Stderr returns the stderr captured so far, bounded per MaxStderrBytes,
+// along with whether it was truncated and how many bytes were elided.
+// It's only meaningful once MaxStderrBytes is set and something has
+// reached StderrScanner, directly or via WithLineHandler; otherwise it
+// returns zero values, since nothing is accumulating stderr to report.
+func (i *Interact) Stderr() (text string, truncated bool, elidedBytes int) {
+	if i.stderrBound == nil {
+		return "", false, 0
+	}
+
+	return i.stderrBound.Result()
+}
+
+// WithLineHandler starts goroutines that scan StdoutScanner and
+// StderrScanner line-by-line and invoke fn for each line as it arrives,
+// tagged with which stream it came from. Call it once, after Pipes are
+// attached. Stop and Wait both join the scanning goroutines before
+// returning, so no callback fires after either returns.
+func (i *Interact) WithLineHandler(fn func(source StreamSource, line []byte)) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	out, err := i.StdoutScanner(), i.StderrScanner()
+	if i.MaxScanTokenSize > 0 {
+		out.Buffer(make([]byte, 0, i.MaxScanTokenSize), i.MaxScanTokenSize)
+		err.Buffer(make([]byte, 0, i.MaxScanTokenSize), i.MaxScanTokenSize)
+	}
+
+	go scanInteractLines(&wg, out, Stdout, fn)
+	go scanInteractLines(&wg, err, Stderr, fn)
+
+	i.lineWG = &wg
+}
+
+// Stop kills the underlying Controller first, then joins any goroutines
+// started by WithLineHandler. Stop exists for a process that won't exit
+// on its own, and those goroutines only return once the pipes hit EOF,
+// which only happens once the process is gone; joining them before
+// killing, the way Wait does, would deadlock forever on a live process.
+// Killing first does mean a few buffered-but-unread bytes can be lost the
+// same way a hard kill always risks losing output, but that's preferable
+// to Stop never returning at all.
+func (i *Interact) Stop() error {
+	err := i.Controller.Stop()
+
+	if i.lineWG != nil {
+		i.lineWG.Wait()
+	}
+
+	return err
+}
+
+// Wait joins any goroutines started by WithLineHandler before waiting on
+// the underlying Controller, the same order Stream uses, since Wait
+// closes the pipes once it sees the process exit; waiting on it first
+// would race with the goroutines' still-pending reads.
+func (i *Interact) Wait() error {
+	if i.lineWG != nil {
+		i.lineWG.Wait()
+	}
+
+	return i.Controller.Wait()
+}
+
+// scanInteractLines reads lines from scanner until EOF, invoking fn with
+// a copy of each line, since scanner.Bytes() is reused on the next Scan.
+func scanInteractLines(wg *sync.WaitGroup, scanner *bufio.Scanner, source StreamSource, fn func(StreamSource, []byte)) {
+	defer wg.Done()
+
+	for scanner.Scan() {
+		line := append([]byte(nil), scanner.Bytes()...)
+		fn(source, line)
+	}
+}
+
 // Controller defines the process control portion of the command and what
 // users can do with it. It is, again, an illustration of possibilities.
 type Controller interface {
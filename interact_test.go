@@ -0,0 +1,125 @@
+package command
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/metrumresearchgroup/wrapt"
+)
+
+func TestInteract_WithLineHandler(tt *testing.T) {
+	t := wrapt.WrapT(tt)
+
+	c := New("bash", "-c", `echo out1; echo out2; echo err1 1>&2`)
+
+	stdin, err := c.StdinPipe()
+	t.R.NoError(err)
+
+	stdout, err := c.StdoutPipe()
+	t.R.NoError(err)
+
+	stderr, err := c.StderrPipe()
+	t.R.NoError(err)
+
+	i := &Interact{
+		Plumber:    &Pipes{Stdin: stdin, Stdout: stdout, Stderr: stderr},
+		Controller: c,
+	}
+
+	var (
+		mu          sync.Mutex
+		stdoutLines []string
+		stderrLines []string
+	)
+
+	i.WithLineHandler(func(source StreamSource, line []byte) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch source {
+		case Stdout:
+			stdoutLines = append(stdoutLines, string(line))
+		case Stderr:
+			stderrLines = append(stderrLines, string(line))
+		}
+	})
+
+	t.R.NoError(c.Start())
+	t.R.NoError(i.Wait())
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	t.R.Equal([]string{"out1", "out2"}, stdoutLines)
+	t.R.Equal([]string{"err1"}, stderrLines)
+}
+
+func TestInteract_MaxStderrBytes(tt *testing.T) {
+	t := wrapt.WrapT(tt)
+
+	c := New("bash", "-c", `for i in $(seq 1 2000); do echo "line $i" 1>&2; done`)
+
+	stdin, err := c.StdinPipe()
+	t.R.NoError(err)
+
+	stdout, err := c.StdoutPipe()
+	t.R.NoError(err)
+
+	stderr, err := c.StderrPipe()
+	t.R.NoError(err)
+
+	i := &Interact{
+		Plumber:        &Pipes{Stdin: stdin, Stdout: stdout, Stderr: stderr},
+		Controller:     c,
+		MaxStderrBytes: 200,
+	}
+
+	i.WithLineHandler(func(source StreamSource, line []byte) {})
+
+	t.R.NoError(c.Start())
+	t.R.NoError(i.Wait())
+
+	got, truncated, elided := i.Stderr()
+	t.R.True(truncated)
+	t.R.True(elided > 0)
+	t.R.True(len(got) < 1024)
+}
+
+// TestInteract_Stop_liveProcess guards against Stop joining lineWG before
+// killing the process: WithLineHandler's goroutines only return on pipe
+// EOF, which only happens once the process is gone, so a process that
+// won't exit on its own (exactly what Stop exists for) would deadlock
+// Stop forever if the kill hadn't already happened first.
+func TestInteract_Stop_liveProcess(tt *testing.T) {
+	t := wrapt.WrapT(tt)
+
+	c := New("bash", "-c", `while :; do sleep 1; done`)
+
+	stdin, err := c.StdinPipe()
+	t.R.NoError(err)
+
+	stdout, err := c.StdoutPipe()
+	t.R.NoError(err)
+
+	stderr, err := c.StderrPipe()
+	t.R.NoError(err)
+
+	i := &Interact{
+		Plumber:    &Pipes{Stdin: stdin, Stdout: stdout, Stderr: stderr},
+		Controller: c,
+	}
+
+	i.WithLineHandler(func(source StreamSource, line []byte) {})
+
+	t.R.NoError(c.Start())
+
+	done := make(chan error, 1)
+	go func() { done <- i.Stop() }()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.R.True(false, "Interact.Stop() deadlocked on a still-running process")
+	}
+}
@@ -0,0 +1,124 @@
+// Package modules lets a test register Go functions and invoke them
+// through command.Capture/CaptureContext as if they were external
+// binaries, by re-executing the test binary itself with a sentinel
+// environment variable. This removes the need to shell out to bash or
+// ship test fixtures just to exercise stderr limits, signal handling, or
+// long-running interactive I/O: the "external process" is deterministic
+// Go code, but command still sees real pipes, a real exit code, and real
+// signal delivery, since it's a genuinely separate process.
+package modules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/metrumresearchgroup/command"
+)
+
+// envVar names the environment variable DispatchAndExitIfChild looks for
+// to tell whether the current process is a re-executed module rather than
+// the top-level `go test` run.
+const envVar = "COMMAND_MODULE"
+
+// Env wires the standard streams and arguments a dispatched module runs
+// with, mirroring what an external process would see as os.Stdin,
+// os.Stdout, os.Stderr, and os.Args[1:].
+type Env struct {
+	Args   []string
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Func is a module's entry point.
+type Func func(env *Env) error
+
+// ExitCoder lets a module's error carry a specific process exit code,
+// the same way *exec.ExitError does for a real external command. An
+// error that doesn't implement it exits 1.
+type ExitCoder interface {
+	ExitCode() int
+}
+
+// Handle identifies a registered module so RunModule can invoke it by
+// name without callers passing raw strings around.
+type Handle struct {
+	name string
+}
+
+var registry = map[string]Func{}
+
+// Register records fn under name so DispatchAndExitIfChild can find it in
+// the re-executed child, and returns a Handle for RunModule to invoke it
+// by. Registering the same name twice panics: it's a programming error
+// caught at init time, not a runtime condition callers need to recover
+// from.
+func Register(name string, fn Func) Handle {
+	if _, exists := registry[name]; exists {
+		panic("modules: " + name + " already registered")
+	}
+
+	registry[name] = fn
+
+	return Handle{name: name}
+}
+
+// DispatchAndExitIfChild checks whether the current process was
+// re-executed to run a registered module, and if so, runs it and exits
+// with its result instead of returning. Call it at the top of TestMain,
+// before m.Run(), so a re-exec'd child never reaches the normal test
+// suite.
+func DispatchAndExitIfChild() {
+	name, ok := os.LookupEnv(envVar)
+	if !ok {
+		return
+	}
+
+	fn, ok := registry[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "modules: no module registered as %q\n", name)
+		os.Exit(1)
+	}
+
+	err := fn(&Env{
+		Args:   os.Args[1:],
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	})
+	if err == nil {
+		os.Exit(0)
+	}
+
+	fmt.Fprintln(os.Stderr, err)
+
+	var coder ExitCoder
+	if errors.As(err, &coder) {
+		os.Exit(coder.ExitCode())
+	}
+
+	os.Exit(1)
+}
+
+// RunModule invokes handle by re-executing the current test binary with
+// envVar set, via command.CaptureContext, so it appears to command like
+// any other external process: real pipes, a real exit code, real signal
+// delivery. A nil env inherits the current process's environment, same
+// as os/exec's own convention for an empty Cmd.Env.
+func RunModule(ctx context.Context, env []string, handle Handle, args ...string) (command.Result, error) {
+	self, err := os.Executable()
+	if err != nil {
+		return command.Result{}, err
+	}
+
+	if env == nil {
+		env = os.Environ()
+	}
+
+	env = append(env, envVar+"="+handle.name)
+
+	return command.CaptureContext(ctx, env, self, args...)
+}
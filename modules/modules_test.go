@@ -0,0 +1,66 @@
+package modules_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/metrumresearchgroup/command/modules"
+)
+
+var echoArgs = modules.Register("echoArgs", func(env *modules.Env) error {
+	for _, a := range env.Args {
+		fmt.Fprintln(env.Stdout, a)
+	}
+
+	return nil
+})
+
+var failWithCode = modules.Register("failWithCode", func(env *modules.Env) error {
+	return exitCodeError(3)
+})
+
+var echoStdin = modules.Register("echoStdin", func(env *modules.Env) error {
+	_, err := io.Copy(env.Stdout, env.Stdin)
+
+	return err
+})
+
+type exitCodeError int
+
+func (e exitCodeError) Error() string { return fmt.Sprintf("exit code %d", int(e)) }
+func (e exitCodeError) ExitCode() int { return int(e) }
+
+func TestMain(m *testing.M) {
+	modules.DispatchAndExitIfChild()
+
+	os.Exit(m.Run())
+}
+
+func TestRunModule(t *testing.T) {
+	got, err := modules.RunModule(context.Background(), nil, echoArgs, "one", "two")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got.Output != "one\ntwo\n" {
+		t.Errorf("mismatch in output: got %q", got.Output)
+	}
+
+	if got.ExitCode != 0 {
+		t.Errorf("mismatch in exit code: got %d", got.ExitCode)
+	}
+}
+
+func TestRunModule_ExitCode(t *testing.T) {
+	got, err := modules.RunModule(context.Background(), nil, failWithCode)
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+
+	if got.ExitCode != 3 {
+		t.Errorf("mismatch in exit code: got %d", got.ExitCode)
+	}
+}
@@ -3,10 +3,14 @@
 package command
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"io"
 	"os/exec"
 	"path"
+	"sync"
+	"time"
 )
 
 // Result represents a single execution of a *exec.Cmd
@@ -23,6 +27,23 @@ type Result struct {
 	// Output provides the combined output from the command as a string.
 	Output string `json:"output,omitempty"`
 
+	// Stdout provides the standard output of the command in isolation.
+	// It is only populated when the capture was made WithSeparateStreams.
+	Stdout string `json:"stdout,omitempty"`
+
+	// Stderr provides the standard error of the command in isolation. It
+	// is populated when the capture was made WithSeparateStreams,
+	// WithMaxStderrBytes, or WithLineHandler.
+	Stderr string `json:"stderr,omitempty"`
+
+	// StderrTruncated reports whether Stderr was elided because the
+	// command exceeded the limit set by WithMaxStderrBytes.
+	StderrTruncated bool `json:"stderrTruncated,omitempty"`
+
+	// StderrElidedBytes is how many bytes of stderr were dropped from the
+	// middle of Stderr when StderrTruncated is true.
+	StderrElidedBytes int `json:"stderrElidedBytes,omitempty"`
+
 	// ExitCode holds the exit code returned by the call.
 	// It will be 0 (default value) even if a command didn't run due to error.
 	// You MUST check error when calling any of the functions below, as the
@@ -30,6 +51,98 @@ type Result struct {
 	ExitCode int `json:"exitCode"`
 }
 
+// Option customizes how a capture executes and records its Result.
+type Option func(*captureOptions)
+
+type captureOptions struct {
+	separateStreams bool
+	maxStderrBytes  int
+	retry           *RetryPolicy
+	shutdownGrace   time.Duration
+	lineHandler     func(source StreamSource, line []byte)
+}
+
+// StreamSource identifies which of a command's standard streams a line
+// passed to WithLineHandler came from.
+type StreamSource int
+
+const (
+	// Stdout identifies a line read from the command's standard output.
+	Stdout StreamSource = iota
+
+	// Stderr identifies a line read from the command's standard error.
+	Stderr
+)
+
+func (s StreamSource) String() string {
+	switch s {
+	case Stdout:
+		return "stdout"
+	case Stderr:
+		return "stderr"
+	default:
+		return "unknown"
+	}
+}
+
+// WithSeparateStreams causes capture to populate Result.Stdout and
+// Result.Stderr independently, in addition to the combined Result.Output.
+// This costs an extra buffer and a small synchronization overhead per
+// stream, so the default CombinedOutput fast path remains the default.
+func WithSeparateStreams() Option {
+	return func(o *captureOptions) {
+		o.separateStreams = true
+	}
+}
+
+// WithMaxStderrBytes caps the in-memory stderr buffer at roughly n bytes:
+// the first n/2 bytes are kept verbatim, the last n/2 bytes are kept as a
+// rolling window, and the two are spliced together with a marker line
+// reporting how many bytes were elided. This is essential for
+// long-running or chatty commands (compilers, R/NONMEM runs) where
+// unbounded stderr can OOM the parent. See Result.StderrTruncated and
+// Result.StderrElidedBytes.
+func WithMaxStderrBytes(n int) Option {
+	return func(o *captureOptions) {
+		o.maxStderrBytes = n
+	}
+}
+
+// WithRetry makes CaptureContextOptions/CaptureOptions retry the command
+// according to policy, via Result.RetryContext, instead of running it once.
+func WithRetry(policy RetryPolicy) Option {
+	return func(o *captureOptions) {
+		o.retry = &policy
+	}
+}
+
+// WithShutdownGrace makes CaptureContextOptions/CaptureOptions react to
+// context cancellation by asking the child to exit gracefully (SIGTERM,
+// or its closest platform equivalent) instead of the default abrupt
+// Process.Kill, only escalating to a hard kill if the child hasn't exited
+// within grace. This matters for children (databases, model engines) that
+// need to flush state before exiting. It has no effect when ctx is never
+// canceled, and is ignored by the retry path, since WithRetry seeds its
+// own Result.RetryContext call rather than running through capture.
+func WithShutdownGrace(grace time.Duration) Option {
+	return func(o *captureOptions) {
+		o.shutdownGrace = grace
+	}
+}
+
+// WithLineHandler registers a callback invoked for every line the command
+// emits on stdout or stderr as it runs, in addition to the buffered
+// Result it still returns once the command exits. fn is called from the
+// same two goroutines captureStreams already uses to copy each stream, so
+// calls for a given StreamSource happen one at a time and in order, but a
+// stdout call can interleave with a stderr call; callers needing to
+// serialize across streams must do their own locking.
+func WithLineHandler(fn func(source StreamSource, line []byte)) Option {
+	return func(o *captureOptions) {
+		o.lineHandler = fn
+	}
+}
+
 // CaptureContext executes an exec.CommandContext and returns Result and error.
 //
 // The Result will always be returned, even if it's incomplete.
@@ -56,20 +169,210 @@ func Capture(env []string, name string, args ...string) (cr Result, err error) {
 	return CaptureContext(context.Background(), env, name, args...)
 }
 
-func capture(cmd *exec.Cmd) (cr Result, err error) {
-	output, err := cmd.CombinedOutput()
+// CaptureContextOptions is CaptureContext with Options applied, e.g. WithSeparateStreams.
+// It takes args as a slice rather than variadic so Options can trail it.
+func CaptureContextOptions(ctx context.Context, env []string, name string, args []string, opts ...Option) (cr Result, err error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var options captureOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if options.retry != nil {
+		// Keep name as given, not path.Base(name): RetryContext runs it
+		// via seed.CaptureContext, which execs seed.Name directly, so a
+		// caller-supplied absolute/relative path must survive into the
+		// first attempt too, not just resolve via PATH like a bare name.
+		seed := Result{Name: name, Args: args, Env: env}
+
+		return seed.RetryContext(ctx, *options.retry)
+	}
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = env
+
+	if options.shutdownGrace > 0 {
+		cmd.Cancel = func() error {
+			return terminateProcess(cmd.Process)
+		}
+		cmd.WaitDelay = options.shutdownGrace
+	}
+
+	return capture(cmd, opts...)
+}
+
+// CaptureOptions is Capture with Options applied, e.g. WithSeparateStreams.
+func CaptureOptions(env []string, name string, args []string, opts ...Option) (cr Result, err error) {
+	return CaptureContextOptions(context.Background(), env, name, args, opts...)
+}
+
+func capture(cmd *exec.Cmd, opts ...Option) (cr Result, err error) {
+	var options captureOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	var output []byte
+	switch {
+	case options.separateStreams || options.maxStderrBytes > 0 || options.lineHandler != nil:
+		var stdout string
 
-	cr = Result{
-		Name:     path.Base(cmd.Path),
-		Args:     cmd.Args[1:],
-		Env:      cmd.Env,
-		Output:   string(output),
-		ExitCode: errToExitCode(err),
+		stdout, cr.Stderr, output, cr.StderrTruncated, cr.StderrElidedBytes, err = captureStreams(cmd, options)
+		if options.separateStreams {
+			cr.Stdout = stdout
+		}
+	default:
+		output, err = cmd.CombinedOutput()
 	}
 
+	cr.Name = path.Base(cmd.Path)
+	cr.Args = cmd.Args[1:]
+	cr.Env = cmd.Env
+	cr.Output = string(output)
+	cr.ExitCode = errToExitCode(err)
+
 	return cr, err
 }
 
+// captureStreams runs cmd with an independent buffer for stdout and,
+// when maxStderrBytes is positive, a boundedWriter for stderr; otherwise
+// stderr gets a plain buffer. A third, mutex-guarded writer tees stdout
+// into a merged buffer so the combined output best-effort preserves the
+// interleaving between the two streams; since stdout and stderr are read
+// by independent goroutines over independent pipes, exact ordering across
+// streams isn't guaranteed. When maxStderrBytes is positive, stderr is
+// never teed into merged live, since that would let the combined output
+// grow without bound even though Result.Stderr itself is capped; instead,
+// the already-bounded stderr text is appended to merged once the command
+// exits, so enabling WithMaxStderrBytes actually bounds Result.Output too.
+// When options.lineHandler is set, a fourth writer per stream splits the
+// bytes on newlines and invokes it live.
+func captureStreams(cmd *exec.Cmd, options captureOptions) (stdout, stderr string, combined []byte, truncated bool, elided int, err error) {
+	var stdoutBuf bytes.Buffer
+	merged := &syncBuffer{}
+
+	stdoutWriters := []io.Writer{&stdoutBuf, merged}
+	stderrWriters := []io.Writer{}
+
+	var stdoutLine, stderrLine *lineWriter
+	if options.lineHandler != nil {
+		stdoutLine = &lineWriter{source: Stdout, onLine: options.lineHandler}
+		stderrLine = &lineWriter{source: Stderr, onLine: options.lineHandler}
+		stdoutWriters = append(stdoutWriters, stdoutLine)
+	}
+
+	cmd.Stdout = io.MultiWriter(stdoutWriters...)
+
+	if options.maxStderrBytes > 0 {
+		bounded := newBoundedWriter(options.maxStderrBytes)
+		stderrWriters = append(stderrWriters, bounded)
+		if stderrLine != nil {
+			stderrWriters = append(stderrWriters, stderrLine)
+		}
+
+		cmd.Stderr = io.MultiWriter(stderrWriters...)
+
+		err = cmd.Run()
+
+		stderr, truncated, elided = bounded.Result()
+		merged.Write([]byte(stderr))
+	} else {
+		var stderrBuf bytes.Buffer
+		stderrWriters = append(stderrWriters, &stderrBuf, merged)
+		if stderrLine != nil {
+			stderrWriters = append(stderrWriters, stderrLine)
+		}
+
+		cmd.Stderr = io.MultiWriter(stderrWriters...)
+
+		err = cmd.Run()
+
+		stderr = stderrBuf.String()
+	}
+
+	if stdoutLine != nil {
+		stdoutLine.flush()
+		stderrLine.flush()
+	}
+
+	return stdoutBuf.String(), stderr, []byte(merged.String()), truncated, elided, err
+}
+
+// lineWriter is an io.Writer that splits the bytes it's given on '\n' and
+// invokes onLine for every complete line as soon as it's seen, buffering
+// any trailing partial line until the next Write completes it, or until
+// flush is called once the command has exited.
+type lineWriter struct {
+	source  StreamSource
+	onLine  func(source StreamSource, line []byte)
+	partial []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.partial = append(w.partial, p...)
+
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+
+		w.onLine(w.source, w.partial[:i])
+		w.partial = w.partial[i+1:]
+	}
+
+	return len(p), nil
+}
+
+// flush reports a final, unterminated line left over once the command
+// has exited, the same way bufio.Scanner returns a last token with no
+// trailing newline.
+func (w *lineWriter) flush() {
+	if len(w.partial) > 0 {
+		w.onLine(w.source, w.partial)
+		w.partial = nil
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent writes, needed because
+// cmd.Stdout and cmd.Stderr are copied from the child process on separate
+// goroutines.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
+
+// WriteLine appends line plus a trailing newline, then trims from the
+// front of the buffer until it no longer exceeds limit bytes.
+func (b *syncBuffer) WriteLine(line string, limit int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.buf.WriteString(line)
+	b.buf.WriteByte('\n')
+
+	if excess := b.buf.Len() - limit; excess > 0 {
+		b.buf.Next(excess)
+	}
+}
+
 // errToExitCode converts potential errors to a nil-able int error code.
 func errToExitCode(err error) int {
 	if err == nil {
@@ -85,7 +388,8 @@ func errToExitCode(err error) int {
 }
 
 // CaptureContext re-runs the Result's parameters in a new shell, recording
-// A result in the same way as CaptureContext.
+// a Result in the same way as CaptureContext. Stdout and Stderr are always
+// captured separately on re-run, in addition to the combined Output.
 func (cr Result) CaptureContext(ctx context.Context) (Result, error) {
 	if ctx == nil {
 		ctx = context.Background()
@@ -95,7 +399,7 @@ func (cr Result) CaptureContext(ctx context.Context) (Result, error) {
 	cmd := exec.CommandContext(ctx, cr.Name, cr.Args...)
 	cmd.Env = cr.Env
 
-	return capture(cmd)
+	return capture(cmd, WithSeparateStreams())
 }
 
 // Capture is the same as CaptureContext without regard for controlling context.Context.
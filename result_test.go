@@ -3,6 +3,8 @@ package command_test
 import (
 	"context"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
 
 	"command"
@@ -153,3 +155,84 @@ func TestResult_Capture(t *testing.T) {
 		t.Errorf("mismatch in name: wanted %s, got %s", want.Name, got.Name)
 	}
 }
+
+func TestCaptureOptions_SeparateStreams(t *testing.T) {
+	got, err := command.CaptureOptions(nil, "/bin/bash", []string{"-c", `echo out; echo err 1>&2`}, command.WithSeparateStreams())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got.Stdout != "out\n" {
+		t.Errorf("mismatch in stdout: got %q", got.Stdout)
+	}
+
+	if got.Stderr != "err\n" {
+		t.Errorf("mismatch in stderr: got %q", got.Stderr)
+	}
+
+	// stdout and stderr are independent pipes read by independent
+	// goroutines, so the merged buffer can only do its best to preserve
+	// interleaving; the relative order between streams isn't guaranteed.
+	if len(got.Output) != len("out\nerr\n") || !strings.Contains(got.Output, "out\n") || !strings.Contains(got.Output, "err\n") {
+		t.Errorf("mismatch in combined output: got %q", got.Output)
+	}
+}
+
+func TestCaptureOptions_LineHandler(t *testing.T) {
+	var (
+		mu          sync.Mutex
+		stdoutLines []string
+		stderrLines []string
+	)
+
+	got, err := command.CaptureOptions(nil, "/bin/bash", []string{"-c", `echo out1; echo out2; echo err1 1>&2`},
+		command.WithLineHandler(func(source command.StreamSource, line []byte) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			switch source {
+			case command.Stdout:
+				stdoutLines = append(stdoutLines, string(line))
+			case command.Stderr:
+				stderrLines = append(stderrLines, string(line))
+			}
+		}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if !reflect.DeepEqual(stdoutLines, []string{"out1", "out2"}) {
+		t.Errorf("mismatch in stdout lines: got %v", stdoutLines)
+	}
+
+	if !reflect.DeepEqual(stderrLines, []string{"err1"}) {
+		t.Errorf("mismatch in stderr lines: got %v", stderrLines)
+	}
+
+	if got.ExitCode != 0 {
+		t.Errorf("mismatch in exitcode: got %d", got.ExitCode)
+	}
+}
+
+func TestResult_CaptureContext_SeparateStreams(t *testing.T) {
+	want, err := command.Capture(nil, "/bin/bash", "-c", "echo out; echo err 1>&2")
+	if err != nil {
+		t.Fatalf("setup failed with error: %v", err)
+	}
+
+	got, err := want.Capture()
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if got.Stdout != "out\n" {
+		t.Errorf("mismatch in stdout: got %q", got.Stdout)
+	}
+
+	if got.Stderr != "err\n" {
+		t.Errorf("mismatch in stderr: got %q", got.Stderr)
+	}
+}
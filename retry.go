@@ -0,0 +1,142 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"os/exec"
+	"time"
+)
+
+// RetryPolicy controls how RetryContext re-runs a failing command.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of times the command may be run,
+	// including the first attempt. Defaults to 1 (no retry) if <= 0.
+	MaxAttempts int
+
+	// InitialBackoff is the delay before the second attempt. Defaults to
+	// 100ms if <= 0.
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the delay between attempts. Defaults to
+	// InitialBackoff if <= 0.
+	MaxBackoff time.Duration
+
+	// Multiplier scales the backoff after each attempt. Defaults to 2 if <= 0.
+	Multiplier float64
+
+	// Jitter multiplies the full-jitter delay: each sleep is a uniformly
+	// random fraction of Jitter times the capped exponential backoff, so a
+	// value below 1 shortens the sleep rather than making it less random.
+	// Defaults to 1 (plain full jitter) if <= 0.
+	Jitter float64
+
+	// Retryable decides whether a given Result/error pair should be
+	// retried. Defaults to DefaultRetryable if nil.
+	Retryable func(Result, error) bool
+}
+
+// DefaultRetryable retries a non-zero exit (an *exec.ExitError), and does
+// not retry a context cancellation or any other error.
+func DefaultRetryable(cr Result, err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var exitErr *exec.ExitError
+
+	return errors.As(err, &exitErr) || cr.ExitCode != 0
+}
+
+// backoff computes the delay before the attempt-th retry (0-indexed),
+// using exponential backoff with full jitter:
+// sleep = rand.Float64() * Jitter * min(MaxBackoff, InitialBackoff*Multiplier^attempt).
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	initial := p.InitialBackoff
+	if initial <= 0 {
+		initial = 100 * time.Millisecond
+	}
+
+	maxBackoff := p.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = initial
+	}
+
+	mult := p.Multiplier
+	if mult <= 0 {
+		mult = 2
+	}
+
+	jitter := p.Jitter
+	if jitter <= 0 {
+		jitter = 1
+	}
+
+	capped := float64(initial) * math.Pow(mult, float64(attempt))
+	if capped > float64(maxBackoff) {
+		capped = float64(maxBackoff)
+	}
+
+	return time.Duration(rand.Float64() * jitter * capped)
+}
+
+// RetryContext re-runs cr's command according to policy, applying
+// exponential backoff with full jitter between attempts, until an attempt
+// is no longer retryable, ctx is done, or MaxAttempts is exhausted.
+//
+// The final Result is always returned. If every attempt was retryable,
+// the returned error wraps the last attempt's error and lists the exit
+// code of every attempt made.
+func (cr Result) RetryContext(ctx context.Context, policy RetryPolicy) (Result, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	retryable := policy.Retryable
+	if retryable == nil {
+		retryable = DefaultRetryable
+	}
+
+	maxAttempts := policy.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 1
+	}
+
+	var (
+		result    Result
+		err       error
+		exitCodes []int
+	)
+
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		result, err = cr.CaptureContext(ctx)
+		exitCodes = append(exitCodes, result.ExitCode)
+
+		if !retryable(result, err) {
+			return result, err
+		}
+
+		if attempt == maxAttempts-1 {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		case <-time.After(policy.backoff(attempt)):
+		}
+	}
+
+	return result, fmt.Errorf("command: all %d attempts failed, exit codes %v: %w", maxAttempts, exitCodes, err)
+}
+
+// Retry is the same as RetryContext without regard for controlling context.Context.
+func (cr Result) Retry(policy RetryPolicy) (Result, error) {
+	return cr.RetryContext(context.Background(), policy)
+}
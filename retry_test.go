@@ -0,0 +1,74 @@
+//go:build !windows
+// +build !windows
+
+package command_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	. "github.com/metrumresearchgroup/wrapt"
+
+	"github.com/metrumresearchgroup/command"
+)
+
+// countingScript writes an incrementing counter to countFile on every
+// invocation and exits non-zero until the counter reaches succeedOn.
+func countingScript(t *T, dir string, succeedOn int) (path, countFile string) {
+	countFile = filepath.Join(dir, "count")
+	t.R.NoError(os.WriteFile(countFile, []byte("0"), 0o600))
+
+	path = filepath.Join(dir, "script.sh")
+	script := `#!/bin/bash
+count=$(($(cat "` + countFile + `") + 1))
+echo -n "$count" > "` + countFile + `"
+if [ "$count" -lt ` + strconv.Itoa(succeedOn) + ` ]; then
+  exit 1
+fi
+exit 0
+`
+	t.R.NoError(os.WriteFile(path, []byte(script), 0o755))
+
+	return path, countFile
+}
+
+func TestResult_RetryContext_SucceedsAfterRetries(tt *testing.T) {
+	t := WrapT(tt)
+
+	dir := t.TempDir()
+	script, countFile := countingScript(t, dir, 3)
+
+	seed := command.Result{Name: script}
+
+	result, err := seed.RetryContext(context.Background(), command.RetryPolicy{
+		MaxAttempts:    5,
+		InitialBackoff: time.Millisecond,
+	})
+
+	t.A.NoError(err)
+	t.A.Equal(0, result.ExitCode)
+
+	got, err := os.ReadFile(countFile)
+	t.A.NoError(err)
+	t.A.Equal("3", string(got))
+}
+
+func TestResult_RetryContext_ExhaustsAttempts(tt *testing.T) {
+	t := WrapT(tt)
+
+	dir := t.TempDir()
+	script, _ := countingScript(t, dir, 100)
+
+	seed := command.Result{Name: script}
+
+	_, err := seed.RetryContext(context.Background(), command.RetryPolicy{
+		MaxAttempts:    2,
+		InitialBackoff: time.Millisecond,
+	})
+
+	t.A.Error(err)
+}
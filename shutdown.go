@@ -0,0 +1,126 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"os"
+	"syscall"
+	"time"
+)
+
+// ShutdownReason reports how a Shutdown call concluded.
+type ShutdownReason int
+
+const (
+	// ShutdownExited means the process exited on its own after being
+	// asked to terminate, within the grace period.
+	ShutdownExited ShutdownReason = iota
+
+	// ShutdownKilled means the grace period (or ctx) expired before the
+	// process exited, so it was escalated to a hard kill.
+	ShutdownKilled
+
+	// ShutdownAlreadyExited means there was nothing to terminate: Shutdown
+	// was called on a Cmd that was never started, or on one whose process
+	// was already gone when the termination request was sent.
+	ShutdownAlreadyExited
+)
+
+func (r ShutdownReason) String() string {
+	switch r {
+	case ShutdownExited:
+		return "exited after shutdown request"
+	case ShutdownKilled:
+		return "killed after grace period expired"
+	case ShutdownAlreadyExited:
+		return "already exited"
+	default:
+		return "unknown"
+	}
+}
+
+// ShutdownError reports the outcome of Shutdown. It is returned even when
+// the process exited cleanly, so callers can branch on Reason.
+// Err unwraps to the underlying Wait error, if any.
+type ShutdownError struct {
+	Reason ShutdownReason
+	Err    error
+}
+
+func (e *ShutdownError) Error() string {
+	if e.Err != nil {
+		return "command: " + e.Reason.String() + ": " + e.Err.Error()
+	}
+
+	return "command: " + e.Reason.String()
+}
+
+func (e *ShutdownError) Unwrap() error {
+	return e.Err
+}
+
+// DefaultShutdownGrace is the grace period Stop gives a process to exit on
+// its own before escalating to a hard kill.
+const DefaultShutdownGrace = 5 * time.Second
+
+// Shutdown asks the process to exit gracefully: it sends SIGTERM (or, when
+// WithKillGroup was set, to the whole process group; on platforms with no
+// SIGTERM equivalent this falls back to the same signal Kill would use),
+// then waits up to grace for it to exit on its own. If grace elapses, or
+// ctx is done first, it escalates to Kill. The returned error is a
+// *ShutdownError describing which of the three outcomes occurred; its Err
+// holds Wait's own error, if any, or for an already-gone process the
+// error from signaling it. Any other error from sending the termination
+// request or the hard kill is returned as is.
+func (c *Cmd) Shutdown(ctx context.Context, grace time.Duration) error {
+	if c.Process == nil {
+		return &ShutdownError{Reason: ShutdownAlreadyExited}
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if err := c.sendTerm(); err != nil {
+		if errors.Is(err, os.ErrProcessDone) || errors.Is(err, syscall.ESRCH) {
+			return &ShutdownError{Reason: ShutdownAlreadyExited, Err: err}
+		}
+
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- c.Wait() }()
+
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+
+	select {
+	case err := <-done:
+		return &ShutdownError{Reason: ShutdownExited, Err: err}
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	if err := c.killProcess(); err != nil {
+		return err
+	}
+
+	return &ShutdownError{Reason: ShutdownKilled, Err: <-done}
+}
+
+// Stop implements Controller by gracefully shutting the process down,
+// giving it DefaultShutdownGrace to exit before escalating to a hard kill.
+func (c *Cmd) Stop() error {
+	return c.Shutdown(context.Background(), DefaultShutdownGrace)
+}
+
+// sendTerm asks the process (or, if killGroup was set, its whole process
+// group) to exit, the same way killProcess dispatches Kill.
+func (c *Cmd) sendTerm() error {
+	if c.killGroup {
+		return terminateGroup(c)
+	}
+
+	return terminateProcess(c.Process)
+}
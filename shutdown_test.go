@@ -0,0 +1,109 @@
+//go:build !windows
+// +build !windows
+
+package command
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/user"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/metrumresearchgroup/wrapt"
+)
+
+func TestCmd_Shutdown_exitsOnTerm(tt *testing.T) {
+	t := wrapt.WrapT(tt)
+
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "caught")
+
+	// bash only acts on a trap between foreground commands, not while one
+	// is running, so loop over short sleeps rather than a single long one.
+	c := New("bash", "-c", `trap 'touch "`+marker+`"; exit 0' TERM; while :; do sleep 1; done`)
+	t.R.NoError(c.Start())
+
+	// Give bash a moment to install the trap before signaling it, or the
+	// signal can arrive while SIGTERM's default (untrapped) disposition
+	// still applies and kill it outright.
+	time.Sleep(100 * time.Millisecond)
+
+	err := c.Shutdown(context.Background(), 2*time.Second)
+
+	var shutdownErr *ShutdownError
+	t.R.True(errors.As(err, &shutdownErr))
+	t.R.Equal(ShutdownExited, shutdownErr.Reason)
+
+	_, statErr := os.Stat(marker)
+	t.R.NoError(statErr)
+}
+
+func TestCmd_Shutdown_killsAfterGraceExpires(tt *testing.T) {
+	t := wrapt.WrapT(tt)
+
+	c := New("bash", "-c", `trap '' TERM; while :; do sleep 1; done`)
+	t.R.NoError(c.Start())
+	time.Sleep(100 * time.Millisecond)
+
+	err := c.Shutdown(context.Background(), 100*time.Millisecond)
+
+	var shutdownErr *ShutdownError
+	t.R.True(errors.As(err, &shutdownErr))
+	t.R.Equal(ShutdownKilled, shutdownErr.Reason)
+}
+
+// TestCmd_Shutdown_impersonateSetPgid_termsGrandchild guards against
+// sendTerm gating group termination on killGroup alone while
+// Impersonate(_, true) forgets to set it: without killGroup set, Shutdown
+// would only SIGTERM the direct child, leaving a grandchild that traps
+// TERM untouched, even though Setpgid put it in a terminable group.
+func TestCmd_Shutdown_impersonateSetPgid_termsGrandchild(tt *testing.T) {
+	t := wrapt.WrapT(tt)
+
+	u, err := user.Current()
+	t.R.NoError(err)
+
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "grandchild-caught")
+
+	c := New("bash", "-c", `(trap 'touch "`+marker+`"; exit 0' TERM; while :; do sleep 1; done) & wait`)
+	t.R.NoError(c.Impersonate(u.Username, true))
+	t.R.NoError(c.Start())
+
+	time.Sleep(100 * time.Millisecond)
+
+	err = c.Shutdown(context.Background(), 2*time.Second)
+
+	var shutdownErr *ShutdownError
+	t.R.True(errors.As(err, &shutdownErr))
+
+	// Shutdown returns as soon as the direct child exits, which races with
+	// the grandchild's own trap handler finishing its touch, so poll
+	// instead of statting once.
+	var caught bool
+
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if _, statErr := os.Stat(marker); statErr == nil {
+			caught = true
+
+			break
+		}
+	}
+
+	t.R.True(caught, "grandchild never caught TERM after Impersonate(_, true).Shutdown()")
+}
+
+func TestCmd_Shutdown_neverStarted(tt *testing.T) {
+	t := wrapt.WrapT(tt)
+
+	c := New("bash", "-c", "exit 0")
+
+	err := c.Shutdown(context.Background(), time.Second)
+
+	var shutdownErr *ShutdownError
+	t.R.True(errors.As(err, &shutdownErr))
+	t.R.Equal(ShutdownAlreadyExited, shutdownErr.Reason)
+}
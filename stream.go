@@ -0,0 +1,129 @@
+package command
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os/exec"
+	"path"
+	"sync"
+
+	"github.com/metrumresearchgroup/command/pipes"
+)
+
+// streamTailLimit bounds how much combined output Stream keeps in
+// Result.Output, so a long-running, chatty command can't grow it without
+// limit while still leaving a tail for diagnostics.
+const streamTailLimit = 64 * 1024
+
+// StreamOption customizes how Stream scans and reports a running command's
+// output.
+type StreamOption func(*streamOptions)
+
+type streamOptions struct {
+	onStdout     func(line string)
+	onStderr     func(line string)
+	maxLineBytes int
+	splitFunc    bufio.SplitFunc
+}
+
+// WithOnStdout registers a callback invoked for every line Stream reads
+// from the command's stdout.
+func WithOnStdout(fn func(line string)) StreamOption {
+	return func(o *streamOptions) {
+		o.onStdout = fn
+	}
+}
+
+// WithOnStderr registers a callback invoked for every line Stream reads
+// from the command's stderr.
+func WithOnStderr(fn func(line string)) StreamOption {
+	return func(o *streamOptions) {
+		o.onStderr = fn
+	}
+}
+
+// WithMaxLineBytes sets the size of the scanning buffer so lines longer
+// than bufio.Scanner's default (64KB) don't cause Stream to fail.
+func WithMaxLineBytes(n int) StreamOption {
+	return func(o *streamOptions) {
+		o.maxLineBytes = n
+	}
+}
+
+// WithSplitFunc swaps in a bufio.SplitFunc other than the default
+// line-oriented bufio.ScanLines, e.g. to tokenize on something other than
+// newlines.
+func WithSplitFunc(fn bufio.SplitFunc) StreamOption {
+	return func(o *streamOptions) {
+		o.splitFunc = fn
+	}
+}
+
+// Stream runs name with args to completion, invoking the OnStdout/OnStderr
+// callbacks registered via StreamOption for every line emitted on stdout
+// and stderr as the process runs, and returns a Result once it exits.
+//
+// Callbacks for a given stream are always called from the same goroutine,
+// one at a time, so callers don't need their own locking.
+func Stream(ctx context.Context, name string, args []string, opts ...StreamOption) (Result, error) {
+	var options streamOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	cmd := exec.CommandContext(ctx, name, args...)
+
+	p, err := pipes.Attach(cmd)
+	if err != nil {
+		return Result{}, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return Result{}, err
+	}
+
+	tail := &syncBuffer{}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	go scanLines(&wg, p.Stdout, options, tail, options.onStdout)
+	go scanLines(&wg, p.Stderr, options, tail, options.onStderr)
+
+	wg.Wait()
+
+	err = cmd.Wait()
+
+	return Result{
+		Name:     path.Base(cmd.Path),
+		Args:     cmd.Args[1:],
+		Env:      cmd.Env,
+		Output:   tail.String(),
+		ExitCode: errToExitCode(err),
+	}, err
+}
+
+// scanLines reads lines from r until EOF, appending each to tail and, if
+// onLine is set, invoking it with the line.
+func scanLines(wg *sync.WaitGroup, r io.Reader, options streamOptions, tail *syncBuffer, onLine func(line string)) {
+	defer wg.Done()
+
+	scanner := bufio.NewScanner(r)
+	if options.splitFunc != nil {
+		scanner.Split(options.splitFunc)
+	}
+	if options.maxLineBytes > 0 {
+		scanner.Buffer(make([]byte, 0, options.maxLineBytes), options.maxLineBytes)
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		tail.WriteLine(line, streamTailLimit)
+
+		if onLine != nil {
+			onLine(line)
+		}
+	}
+}
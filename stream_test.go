@@ -0,0 +1,41 @@
+//go:build !windows
+// +build !windows
+
+package command_test
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/metrumresearchgroup/wrapt"
+
+	"github.com/metrumresearchgroup/command"
+)
+
+func TestStream(tt *testing.T) {
+	t := WrapT(tt)
+
+	var stdout, stderr []string
+
+	result, err := command.Stream(
+		context.Background(),
+		"/bin/bash",
+		[]string{"-c", `echo out; echo err 1>&2`},
+		command.WithOnStdout(func(line string) { stdout = append(stdout, line) }),
+		command.WithOnStderr(func(line string) { stderr = append(stderr, line) }),
+	)
+
+	t.A.NoError(err)
+	t.A.Equal(0, result.ExitCode)
+	t.A.Equal([]string{"out"}, stdout)
+	t.A.Equal([]string{"err"}, stderr)
+}
+
+func TestStream_NonzeroExit(tt *testing.T) {
+	t := WrapT(tt)
+
+	result, err := command.Stream(context.Background(), "/bin/bash", []string{"-c", "exit 3"})
+
+	t.A.Error(err)
+	t.A.Equal(3, result.ExitCode)
+}